@@ -0,0 +1,28 @@
+package cloudwatch
+
+import (
+	"context"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// FilterLogEventsAPI is the minimal subset of *cloudwatchlogsV2.Client that
+// Tail, LsStreams, and Source need. It's extracted so tests can supply a
+// fake (see the cwfake subpackage) instead of making real AWS calls; the
+// concrete SDK client satisfies it unchanged.
+type FilterLogEventsAPI interface {
+	FilterLogEvents(ctx context.Context, params *cloudwatchlogsV2.FilterLogEventsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.FilterLogEventsOutput, error)
+	DescribeLogStreams(ctx context.Context, params *cloudwatchlogsV2.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogStreamsOutput, error)
+	DescribeLogGroups(ctx context.Context, params *cloudwatchlogsV2.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogGroupsOutput, error)
+}
+
+// QueryAPI is the subset of *cloudwatchlogsV2.Client that Query needs,
+// extracted for the same reason as FilterLogEventsAPI: so tests can fake
+// the Logs Insights start/poll/stop cycle instead of making real AWS
+// calls.
+type QueryAPI interface {
+	StartQuery(ctx context.Context, params *cloudwatchlogsV2.StartQueryInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogsV2.GetQueryResultsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.GetQueryResultsOutput, error)
+	StopQuery(ctx context.Context, params *cloudwatchlogsV2.StopQueryInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.StopQueryOutput, error)
+	DescribeLogGroups(ctx context.Context, params *cloudwatchlogsV2.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogGroupsOutput, error)
+}
@@ -0,0 +1,100 @@
+package cloudwatch
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultPurgeFreq is how often a ttlCache sweeps for expired entries.
+const defaultPurgeFreq = 30 * time.Second
+
+// ttlCache is the event-id dedup cache Tail uses to avoid re-delivering an
+// event FilterLogEvents returns again on a later poll. Has/Add are driven
+// off the event id; entries older than ttl are swept out on a background
+// ticker so memory doesn't grow unbounded while tailing a busy log group.
+// Callers must call Stop once the cache is no longer needed, or the purge
+// goroutine and its ticker leak for the life of the process.
+type ttlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+	log     *log.Logger
+	done    chan struct{}
+}
+
+// createCache builds a ttlCache that expires entries after ttl, sweeping
+// for expired entries every purgeFreq. Call Stop when the cache is no
+// longer needed.
+func createCache(ttl time.Duration, purgeFreq time.Duration, logger *log.Logger) *ttlCache {
+	c := &ttlCache{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+		log:     logger,
+		done:    make(chan struct{}),
+	}
+	go c.purgeLoop(purgeFreq)
+	return c
+}
+
+// Stop ends the background purge loop. Safe to call once; further Add/Has
+// calls still work, they just stop being swept.
+func (c *ttlCache) Stop() {
+	close(c.done)
+}
+
+func (c *ttlCache) purgeLoop(purgeFreq time.Duration) {
+	t := time.NewTicker(purgeFreq)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-t.C:
+			c.purge()
+		}
+	}
+}
+
+func (c *ttlCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	purged := 0
+	for id, addedAt := range c.entries {
+		if now.Sub(addedAt) > c.ttl {
+			delete(c.entries, id)
+			purged++
+		}
+	}
+	if purged > 0 && c.log != nil {
+		c.log.Printf("cache: purged %d expired entries, %d remaining\n", purged, len(c.entries))
+	}
+}
+
+// Add records id as seen. The timestamp argument mirrors the event
+// timestamp callers already have in hand; expiry itself is wall-clock
+// based off when Add was called, not off this value.
+func (c *ttlCache) Add(id string, _ int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[id] = time.Now()
+}
+
+// Has reports whether id was added within the last ttl.
+func (c *ttlCache) Has(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	addedAt, ok := c.entries[id]
+	if !ok {
+		return false
+	}
+	return time.Since(addedAt) <= c.ttl
+}
+
+// Size returns the number of entries currently tracked.
+func (c *ttlCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
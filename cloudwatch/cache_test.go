@@ -0,0 +1,46 @@
+package cloudwatch
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestTtlCache_StopEndsThePurgeGoroutine guards against the purge loop
+// leaking forever: Stop must make purgeLoop return rather than just being
+// a no-op cleanup hook.
+func TestTtlCache_StopEndsThePurgeGoroutine(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c := createCache(time.Second, time.Millisecond, discardLogger())
+	c.Add("1", 0)
+
+	// Give purgeLoop's goroutine a chance to actually start before we stop
+	// it, so the NumGoroutine comparison below isn't comparing against a
+	// scheduler no-op.
+	time.Sleep(10 * time.Millisecond)
+	if got := runtime.NumGoroutine(); got <= before {
+		t.Fatalf("expected createCache to have started a goroutine, NumGoroutine went from %d to %d", before, got)
+	}
+
+	c.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the purge goroutine to exit after Stop, NumGoroutine stayed at %d (baseline %d)", runtime.NumGoroutine(), before)
+}
+
+func TestTtlCache_HasAndAddStillWorkAfterStop(t *testing.T) {
+	c := createCache(time.Minute, time.Millisecond, discardLogger())
+	c.Stop()
+
+	c.Add("1", 0)
+	if !c.Has("1") {
+		t.Fatal("expected Has to find an entry added after Stop")
+	}
+}
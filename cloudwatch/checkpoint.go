@@ -0,0 +1,152 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// checkpointRingSize bounds how many recent event IDs a Checkpointer
+// persists for dedup purposes. The in-memory TTL cache Tail keeps is
+// bounded by entry age, not by count, so the two sizes are independent.
+const checkpointRingSize = 10000
+
+// State is what a Checkpointer persists between Tail restarts so a
+// restarted tail neither replays nor drops events.
+type State struct {
+	LogGroupName      string   `json:"log_group_name"`
+	LastSeenTimestamp int64    `json:"last_seen_timestamp"`
+	EventIDs          []string `json:"event_ids"`
+	Streams           []string `json:"streams"`
+}
+
+// pushEventID appends id to the ring, evicting the oldest entries once
+// checkpointRingSize is exceeded.
+func (s *State) pushEventID(id string) {
+	s.EventIDs = append(s.EventIDs, id)
+	if over := len(s.EventIDs) - checkpointRingSize; over > 0 {
+		s.EventIDs = s.EventIDs[over:]
+	}
+}
+
+// Checkpointer loads and saves the Tail State for a log group so a
+// restarted process can resume where it left off.
+type Checkpointer interface {
+	// Load returns the saved State for group, or a fresh State seeded
+	// with streams if nothing has been saved yet.
+	Load(group string, streams []string) (State, error)
+	Save(group string, state State) error
+}
+
+var checkpointFileSanitizer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileCheckpointer persists one JSON file per log group under Dir.
+type FileCheckpointer struct {
+	Dir string
+}
+
+// NewFileCheckpointer returns a FileCheckpointer rooted at dir, creating
+// it if it doesn't already exist.
+func NewFileCheckpointer(dir string) (*FileCheckpointer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating checkpoint dir %s: %w", dir, err)
+	}
+	return &FileCheckpointer{Dir: dir}, nil
+}
+
+func (f *FileCheckpointer) path(group string) string {
+	return filepath.Join(f.Dir, checkpointFileSanitizer.ReplaceAllString(group, "_")+".json")
+}
+
+// Load reads the checkpoint file for group, returning a fresh State seeded
+// with streams if none has been saved yet.
+func (f *FileCheckpointer) Load(group string, streams []string) (State, error) {
+	b, err := ioutil.ReadFile(f.path(group))
+	if errors.Is(err, os.ErrNotExist) {
+		return State{LogGroupName: group, Streams: streams}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return State{}, fmt.Errorf("parsing checkpoint for %s: %w", group, err)
+	}
+	return s, nil
+}
+
+// Save writes state for group, via a temp-file-then-rename so a crash
+// mid-write can't leave a half-written checkpoint behind.
+func (f *FileCheckpointer) Save(group string, state State) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	dest := f.path(group)
+	tmp := dest + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// BoltCheckpointer persists one State per log group in a BoltDB file,
+// keyed by logGroupName.
+type BoltCheckpointer struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+var checkpointBucket = []byte("cw_checkpoints")
+
+// NewBoltCheckpointer opens (creating if necessary) a BoltDB database at
+// path to store checkpoints in.
+func NewBoltCheckpointer(path string) (*BoltCheckpointer, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint db %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(checkpointBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltCheckpointer{db: db, bucket: checkpointBucket}, nil
+}
+
+// Load returns the saved State for group, or a fresh State seeded with
+// streams if nothing has been saved yet.
+func (b *BoltCheckpointer) Load(group string, streams []string) (State, error) {
+	state := State{LogGroupName: group, Streams: streams}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(b.bucket).Get([]byte(group))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &state)
+	})
+	return state, err
+}
+
+func (b *BoltCheckpointer) Save(group string, state State) error {
+	v, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(b.bucket).Put([]byte(group), v)
+	})
+}
+
+// Close releases the underlying BoltDB file.
+func (b *BoltCheckpointer) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,226 @@
+package cloudwatch
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Mimie1805/cw/cloudwatch/cwfake"
+)
+
+func TestState_PushEventIDEvictsOldestPastRingSize(t *testing.T) {
+	var s State
+	for i := 0; i < checkpointRingSize+10; i++ {
+		s.pushEventID(string(rune('a' + i%26)))
+	}
+	if len(s.EventIDs) != checkpointRingSize {
+		t.Fatalf("got %d event ids, want ring capped at %d", len(s.EventIDs), checkpointRingSize)
+	}
+	// The first 10 ids pushed (a..j) are the ones evicted, so the oldest
+	// survivor is the 11th, "k".
+	if s.EventIDs[0] != "k" {
+		t.Fatalf("got oldest surviving id %q, want %q once the 10 oldest are evicted", s.EventIDs[0], "k")
+	}
+}
+
+func TestFileCheckpointer_LoadSeedsFreshStateWhenNothingSaved(t *testing.T) {
+	f, err := NewFileCheckpointer(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileCheckpointer: %s", err)
+	}
+
+	got, err := f.Load("my-group", []string{"s1", "s2"})
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	want := State{LogGroupName: "my-group", Streams: []string{"s1", "s2"}}
+	if got.LogGroupName != want.LogGroupName || len(got.Streams) != len(want.Streams) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFileCheckpointer_SaveLoadRoundTrips(t *testing.T) {
+	f, err := NewFileCheckpointer(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileCheckpointer: %s", err)
+	}
+
+	want := State{
+		LogGroupName:      "my-group",
+		LastSeenTimestamp: 12345,
+		EventIDs:          []string{"a", "b", "c"},
+		Streams:           []string{"s1"},
+	}
+	if err := f.Save("my-group", want); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	got, err := f.Load("my-group", nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if got.LastSeenTimestamp != want.LastSeenTimestamp || len(got.EventIDs) != len(want.EventIDs) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFileCheckpointer_SaveLeavesNoTempFileBehind(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileCheckpointer(dir)
+	if err != nil {
+		t.Fatalf("NewFileCheckpointer: %s", err)
+	}
+
+	if err := f.Save("my-group", State{LogGroupName: "my-group"}); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatalf("Glob: %s", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected the atomic rename to leave no .tmp file behind, found %v", matches)
+	}
+}
+
+func TestFileCheckpointer_SanitizesGroupNameForFilename(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileCheckpointer(dir)
+	if err != nil {
+		t.Fatalf("NewFileCheckpointer: %s", err)
+	}
+
+	if err := f.Save("/aws/lambda/my-fn", State{LogGroupName: "/aws/lambda/my-fn"}); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("Glob: %s", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected exactly one checkpoint file, got %v", matches)
+	}
+}
+
+func TestBoltCheckpointer_SaveLoadRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.db")
+	b, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("NewBoltCheckpointer: %s", err)
+	}
+	defer b.Close()
+
+	want := State{
+		LogGroupName:      "my-group",
+		LastSeenTimestamp: 6789,
+		EventIDs:          []string{"x", "y"},
+		Streams:           []string{"s1", "s2"},
+	}
+	if err := b.Save("my-group", want); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	got, err := b.Load("my-group", nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if got.LastSeenTimestamp != want.LastSeenTimestamp || len(got.EventIDs) != len(want.EventIDs) || len(got.Streams) != len(want.Streams) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBoltCheckpointer_LoadSeedsFreshStateWhenNothingSaved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.db")
+	b, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("NewBoltCheckpointer: %s", err)
+	}
+	defer b.Close()
+
+	got, err := b.Load("unseen-group", []string{"s1"})
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if got.LogGroupName != "unseen-group" || len(got.Streams) != 1 {
+		t.Fatalf("got %+v, want seeded fresh state", got)
+	}
+}
+
+func TestTail_SeedsFromCheckpointAndSavesAfterPage(t *testing.T) {
+	checkpointer, err := NewFileCheckpointer(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileCheckpointer: %s", err)
+	}
+	if err := checkpointer.Save("my-group", State{
+		LogGroupName:      "my-group",
+		LastSeenTimestamp: 5000,
+		EventIDs:          []string{"already-seen"},
+	}); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	fake := &cwfake.Client{FilterPages: []cwfake.FilterLogEventsPage{
+		cwfake.Page(
+			cwfake.Event("already-seen", "s", "replayed", 4000),
+			cwfake.Event("new", "s", "fresh", 6000),
+		),
+	}}
+	limiter := make(chan time.Time, 1)
+	limiter <- time.Now()
+
+	logGroup, logStream, grep, grepv := "my-group", "", "", ""
+	follow, retry := false, false
+	start, end := time.Unix(0, 0), time.Time{}
+	opts := WithCheckpoint(checkpointer, time.Hour)
+
+	ch, _, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, discardLogger(), opts)
+	if err != nil {
+		t.Fatalf("Tail returned error: %s", err)
+	}
+
+	var gotIDs []string
+	for event := range ch {
+		gotIDs = append(gotIDs, *event.EventId)
+	}
+	if len(gotIDs) != 1 || gotIDs[0] != "new" {
+		t.Fatalf("expected the event already in the checkpoint's dedup ring to be skipped, got %v", gotIDs)
+	}
+
+	saved, err := checkpointer.Load("my-group", nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if saved.LastSeenTimestamp != 6000 {
+		t.Fatalf("got saved LastSeenTimestamp %d, want 6000 to be persisted after the page", saved.LastSeenTimestamp)
+	}
+}
+
+func TestBoltCheckpointer_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.db")
+	b, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("NewBoltCheckpointer: %s", err)
+	}
+	if err := b.Save("my-group", State{LogGroupName: "my-group", LastSeenTimestamp: 42}); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	reopened, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("NewBoltCheckpointer (reopen): %s", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Load("my-group", nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if got.LastSeenTimestamp != 42 {
+		t.Fatalf("got LastSeenTimestamp %d, want 42 to survive reopen", got.LastSeenTimestamp)
+	}
+}
@@ -0,0 +1,137 @@
+// Package cwfake provides a scriptable fake satisfying
+// cloudwatch.FilterLogEventsAPI and cloudwatch.QueryAPI, so tests can
+// exercise Tail, LsStreams, Source, and Query against canned paginated
+// responses, injected errors, and clock-controlled event timestamps
+// instead of hitting AWS.
+package cwfake
+
+import (
+	"context"
+	"errors"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// FilterLogEventsPage scripts a single FilterLogEvents response: set
+// either Output (success) or Err (failure), never both.
+type FilterLogEventsPage struct {
+	Output *cloudwatchlogsV2.FilterLogEventsOutput
+	Err    error
+}
+
+// QueryResultsPage scripts a single GetQueryResults response: set either
+// Output (success) or Err (failure), never both.
+type QueryResultsPage struct {
+	Output *cloudwatchlogsV2.GetQueryResultsOutput
+	Err    error
+}
+
+// Client is a scriptable fake CloudWatch Logs client. FilterLogEvents
+// replays FilterPages in order, one page per call; once exhausted it
+// returns an empty page. DescribeLogStreams and DescribeLogGroups return
+// whatever was configured, or DescribeStreamsErr/DescribeGroupsErr.
+// StartQuery/GetQueryResults/StopQuery back Query the same way.
+type Client struct {
+	FilterPages []FilterLogEventsPage
+	filterCalls int
+
+	LogStreams         []types.LogStream
+	DescribeStreamsErr error
+
+	LogGroups         []types.LogGroup
+	DescribeGroupsErr error
+
+	StartQueryID  string
+	StartQueryErr error
+
+	QueryResultPages []QueryResultsPage
+	queryResultCalls int
+
+	StopQueryErr   error
+	StopQueryCalls int
+}
+
+func (c *Client) FilterLogEvents(ctx context.Context, params *cloudwatchlogsV2.FilterLogEventsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.FilterLogEventsOutput, error) {
+	if c.filterCalls >= len(c.FilterPages) {
+		return &cloudwatchlogsV2.FilterLogEventsOutput{}, nil
+	}
+	page := c.FilterPages[c.filterCalls]
+	c.filterCalls++
+	if page.Err != nil {
+		return nil, page.Err
+	}
+	return page.Output, nil
+}
+
+func (c *Client) DescribeLogStreams(ctx context.Context, params *cloudwatchlogsV2.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogStreamsOutput, error) {
+	if c.DescribeStreamsErr != nil {
+		return nil, c.DescribeStreamsErr
+	}
+	return &cloudwatchlogsV2.DescribeLogStreamsOutput{LogStreams: c.LogStreams}, nil
+}
+
+func (c *Client) DescribeLogGroups(ctx context.Context, params *cloudwatchlogsV2.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogGroupsOutput, error) {
+	if c.DescribeGroupsErr != nil {
+		return nil, c.DescribeGroupsErr
+	}
+	return &cloudwatchlogsV2.DescribeLogGroupsOutput{LogGroups: c.LogGroups}, nil
+}
+
+func (c *Client) StartQuery(ctx context.Context, params *cloudwatchlogsV2.StartQueryInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.StartQueryOutput, error) {
+	if c.StartQueryErr != nil {
+		return nil, c.StartQueryErr
+	}
+	id := c.StartQueryID
+	if id == "" {
+		id = "fake-query-id"
+	}
+	return &cloudwatchlogsV2.StartQueryOutput{QueryId: &id}, nil
+}
+
+func (c *Client) GetQueryResults(ctx context.Context, params *cloudwatchlogsV2.GetQueryResultsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.GetQueryResultsOutput, error) {
+	if c.queryResultCalls >= len(c.QueryResultPages) {
+		return &cloudwatchlogsV2.GetQueryResultsOutput{Status: types.QueryStatusComplete}, nil
+	}
+	page := c.QueryResultPages[c.queryResultCalls]
+	c.queryResultCalls++
+	if page.Err != nil {
+		return nil, page.Err
+	}
+	return page.Output, nil
+}
+
+func (c *Client) StopQuery(ctx context.Context, params *cloudwatchlogsV2.StopQueryInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.StopQueryOutput, error) {
+	c.StopQueryCalls++
+	if c.StopQueryErr != nil {
+		return nil, c.StopQueryErr
+	}
+	return &cloudwatchlogsV2.StopQueryOutput{}, nil
+}
+
+// ThrottlingError builds a synthetic ThrottlingException, matching the
+// string cloudwatch.isRetryable checks for.
+func ThrottlingError() error {
+	return errors.New("ThrottlingException: Rate exceeded")
+}
+
+// Page is a convenience constructor for a successful FilterLogEventsPage.
+func Page(events ...types.FilteredLogEvent) FilterLogEventsPage {
+	return FilterLogEventsPage{Output: &cloudwatchlogsV2.FilterLogEventsOutput{Events: events}}
+}
+
+// ErrorPage is a convenience constructor for a failing FilterLogEventsPage.
+func ErrorPage(err error) FilterLogEventsPage {
+	return FilterLogEventsPage{Err: err}
+}
+
+// Event builds a FilteredLogEvent with a clock-controlled timestamp (unix
+// millis), for deterministic ordering and dedup assertions.
+func Event(id, stream, message string, timestampMillis int64) types.FilteredLogEvent {
+	return types.FilteredLogEvent{
+		EventId:       &id,
+		LogStreamName: &stream,
+		Message:       &message,
+		Timestamp:     &timestampMillis,
+	}
+}
@@ -0,0 +1,47 @@
+package cloudwatch
+
+import (
+	"context"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// LsStreams lists the stream names in logGroupName matching logStreamName
+// (a prefix, or "*"/""  for all of them), streaming names as
+// DescribeLogStreams pages come back so a caller can start consuming
+// before the whole log group has been paged through. The first parameter
+// is unused; it exists to keep this call-compatible with the v1-SDK-era
+// signature call sites still pass a nil client through.
+func LsStreams(_ interface{}, cwlV2 FilterLogEventsAPI, logGroupName *string, logStreamName *string) (<-chan *string, <-chan error) {
+	out := make(chan *string)
+	errCh := make(chan error, 1)
+
+	var prefix *string
+	if logStreamName != nil && *logStreamName != "" && *logStreamName != "*" {
+		prefix = logStreamName
+	}
+
+	go func() {
+		defer close(out)
+		p := cloudwatchlogsV2.NewDescribeLogStreamsPaginator(cwlV2, &cloudwatchlogsV2.DescribeLogStreamsInput{
+			LogGroupName:        logGroupName,
+			LogStreamNamePrefix: prefix,
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(context.TODO())
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for _, st := range page.LogStreams {
+				if st.LogStreamName == nil {
+					continue
+				}
+				name := *st.LogStreamName
+				out <- &name
+			}
+		}
+	}()
+
+	return out, errCh
+}
@@ -0,0 +1,143 @@
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tailMetrics bundles the Prometheus collectors a single Tail call
+// publishes. A nil *tailMetrics (the case when no MetricsRegisterer was
+// supplied) makes every method a no-op, so call sites never need to
+// branch on whether metrics are enabled.
+type tailMetrics struct {
+	group string
+
+	openStreams        *prometheus.GaugeVec
+	streamHits         *prometheus.CounterVec
+	filterRequests     *prometheus.CounterVec
+	filterDuration     *prometheus.HistogramVec
+	eventsDropped      *prometheus.CounterVec
+	lastEventTimestamp *prometheus.GaugeVec
+}
+
+// newTailMetrics registers the cw_* collectors on reg, reusing whatever is
+// already registered under the same name so that multiple Tail calls can
+// share one Registerer (e.g. the process default). Returns nil, nil if reg
+// is nil. Returns an error, rather than panicking, if reg already has a
+// same-named collector registered with incompatible label dimensions.
+func newTailMetrics(reg prometheus.Registerer, group string) (*tailMetrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	openStreams, err := registerOrExisting(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cw_openstreams_total",
+		Help: "Number of log stream names in the current FilterLogEvents request; 0 when the whole group is tailed without a stream list.",
+	}, []string{"group"}))
+	if err != nil {
+		return nil, err
+	}
+	streamHits, err := registerOrExisting(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cw_stream_hits_total",
+		Help: "Events received, by log stream.",
+	}, []string{"group", "stream"}))
+	if err != nil {
+		return nil, err
+	}
+	filterRequests, err := registerOrExisting(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cw_filter_requests_total",
+		Help: "FilterLogEvents page fetches, by result (ok|throttled|error).",
+	}, []string{"group", "result"}))
+	if err != nil {
+		return nil, err
+	}
+	filterDuration, err := registerOrExisting(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cw_filter_request_duration_seconds",
+		Help: "FilterLogEvents page fetch latency, one sample per attempt; retry backoff is not included.",
+	}, []string{"group"}))
+	if err != nil {
+		return nil, err
+	}
+	eventsDropped, err := registerOrExisting(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cw_events_dropped_total",
+		Help: "Events dropped because the output channel was full.",
+	}, []string{"group"}))
+	if err != nil {
+		return nil, err
+	}
+	lastEventTimestamp, err := registerOrExisting(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cw_last_event_timestamp_seconds",
+		Help: "Timestamp of the most recently received event.",
+	}, []string{"group"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tailMetrics{
+		group:              group,
+		openStreams:        openStreams.(*prometheus.GaugeVec),
+		streamHits:         streamHits.(*prometheus.CounterVec),
+		filterRequests:     filterRequests.(*prometheus.CounterVec),
+		filterDuration:     filterDuration.(*prometheus.HistogramVec),
+		eventsDropped:      eventsDropped.(*prometheus.CounterVec),
+		lastEventTimestamp: lastEventTimestamp.(*prometheus.GaugeVec),
+	}, nil
+}
+
+// registerOrExisting registers c on reg, returning whatever collector is
+// already registered under the same descriptor. Returns an error, rather
+// than panicking, if c conflicts with a collector already registered under
+// an incompatible descriptor.
+func registerOrExisting(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector, nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *tailMetrics) setOpenStreams(n int) {
+	if m == nil {
+		return
+	}
+	m.openStreams.WithLabelValues(m.group).Set(float64(n))
+}
+
+func (m *tailMetrics) incStreamHit(stream string) {
+	if m == nil {
+		return
+	}
+	m.streamHits.WithLabelValues(m.group, stream).Inc()
+}
+
+func (m *tailMetrics) observeFilterRequest(d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+		if isRetryable(err) {
+			result = "throttled"
+		}
+	}
+	m.filterRequests.WithLabelValues(m.group, result).Inc()
+	m.filterDuration.WithLabelValues(m.group).Observe(d.Seconds())
+}
+
+func (m *tailMetrics) incEventsDropped() {
+	if m == nil {
+		return
+	}
+	m.eventsDropped.WithLabelValues(m.group).Inc()
+}
+
+func (m *tailMetrics) setLastEventTimestamp(millis int64) {
+	if m == nil {
+		return
+	}
+	m.lastEventTimestamp.WithLabelValues(m.group).Set(float64(millis) / 1000)
+}
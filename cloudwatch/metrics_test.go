@@ -0,0 +1,53 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewTailMetrics_NilRegistererIsANoOp(t *testing.T) {
+	m, err := newTailMetrics(nil, "my-group")
+	if err != nil {
+		t.Fatalf("newTailMetrics: %s", err)
+	}
+	if m != nil {
+		t.Fatalf("expected a nil *tailMetrics when no registerer is supplied, got %+v", m)
+	}
+}
+
+func TestNewTailMetrics_SharesCollectorsAcrossCalls(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := newTailMetrics(reg, "group-a"); err != nil {
+		t.Fatalf("first newTailMetrics: %s", err)
+	}
+	if _, err := newTailMetrics(reg, "group-b"); err != nil {
+		t.Fatalf("second newTailMetrics on the same registerer: %s", err)
+	}
+}
+
+// TestNewTailMetrics_ReturnsErrorInsteadOfPanickingOnConflict guards
+// against registerOrExisting panicking (and crashing the host process) when
+// a caller's Registerer already has a same-named collector registered with
+// incompatible label dimensions - an easy mistake when sharing the default
+// registry.
+func TestNewTailMetrics_ReturnsErrorInsteadOfPanickingOnConflict(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	conflicting := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cw_openstreams_total",
+		Help: "a different help string, but that's enough to conflict",
+	}, []string{"group", "extra_label"})
+	if err := reg.Register(conflicting); err != nil {
+		t.Fatalf("registering the conflicting collector: %s", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("newTailMetrics must return the conflict as an error, not panic: %v", r)
+		}
+	}()
+
+	if _, err := newTailMetrics(reg, "my-group"); err == nil {
+		t.Fatal("expected an error for the conflicting collector registration")
+	}
+}
@@ -0,0 +1,34 @@
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TailOptions bundles the optional knobs Tail accepts beyond its
+// positional arguments. The zero value (or a nil *TailOptions) gives the
+// previous defaults: DefaultRetryPolicy(), no metrics, no checkpointing.
+type TailOptions struct {
+	// RetryPolicy controls backoff on transient FilterLogEvents errors.
+	// Defaults to DefaultRetryPolicy() when nil.
+	RetryPolicy *RetryPolicy
+
+	// MetricsRegisterer, when set, publishes the cw_* Prometheus
+	// collectors described in newTailMetrics. Left nil, metrics are a
+	// no-op.
+	MetricsRegisterer prometheus.Registerer
+
+	// Checkpoint, when set, seeds lastSeenTimestamp and the dedup cache
+	// from Checkpoint.Load at startup, and persists state to
+	// Checkpoint.Save after every successful page, at most once per
+	// CheckpointFlushInterval.
+	Checkpoint              Checkpointer
+	CheckpointFlushInterval time.Duration
+}
+
+// WithCheckpoint returns TailOptions configured to resume from, and
+// periodically save to, c, flushing at most once per flushInterval.
+func WithCheckpoint(c Checkpointer, flushInterval time.Duration) *TailOptions {
+	return &TailOptions{Checkpoint: c, CheckpointFlushInterval: flushInterval}
+}
@@ -0,0 +1,148 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// ResultRow is one row of a Logs Insights query result, keyed by field
+// name (e.g. "@timestamp", "@message", or a computed field from the
+// query).
+type ResultRow map[string]string
+
+// QueryInput describes a CloudWatch Logs Insights query. LogGroupNames and
+// LogGroupNamePrefixes may both be set; prefixes are resolved to concrete
+// log group names via DescribeLogGroups before the query starts.
+type QueryInput struct {
+	Query                string
+	StartTime            time.Time
+	EndTime              time.Time
+	Limit                int32
+	LogGroupNames        []string
+	LogGroupNamePrefixes []string
+}
+
+// queryPollInterval is how often GetQueryResults is polled while a query
+// is still running. It's a var rather than a const so tests can speed up
+// polling instead of waiting out the real interval.
+var queryPollInterval = 1 * time.Second
+
+// Query starts a Logs Insights query across one or more log groups and
+// streams result rows as they become available, polling GetQueryResults
+// until the query reaches a terminal status. Cancelling ctx stops the
+// query server-side via StopQuery and closes the returned channel.
+func Query(ctx context.Context, cwlV2 QueryAPI, input QueryInput) (<-chan ResultRow, error) {
+	groupNames, err := resolveLogGroupNames(ctx, cwlV2, input)
+	if err != nil {
+		return nil, fmt.Errorf("resolving log groups: %w", err)
+	}
+	if len(groupNames) == 0 {
+		return nil, fmt.Errorf("no log groups matched")
+	}
+
+	startInput := &cloudwatchlogsV2.StartQueryInput{
+		QueryString:   &input.Query,
+		LogGroupNames: groupNames,
+		StartTime:     awsInt64(input.StartTime.Unix()),
+		EndTime:       awsInt64(input.EndTime.Unix()),
+	}
+	if input.Limit > 0 {
+		startInput.Limit = &input.Limit
+	}
+
+	started, err := cwlV2.StartQuery(ctx, startInput)
+	if err != nil {
+		return nil, fmt.Errorf("starting query: %w", err)
+	}
+	queryID := *started.QueryId
+
+	rows := make(chan ResultRow)
+	go func() {
+		defer close(rows)
+		pollQuery(ctx, cwlV2, queryID, rows)
+	}()
+	return rows, nil
+}
+
+func pollQuery(ctx context.Context, cwlV2 QueryAPI, queryID string, rows chan<- ResultRow) {
+	ticker := time.NewTicker(queryPollInterval)
+	defer ticker.Stop()
+
+	stop := func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		cwlV2.StopQuery(stopCtx, &cloudwatchlogsV2.StopQueryInput{QueryId: &queryID})
+	}
+
+	sent := 0
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			return
+		case <-ticker.C:
+			if ctx.Err() != nil {
+				stop()
+				return
+			}
+			res, err := cwlV2.GetQueryResults(ctx, &cloudwatchlogsV2.GetQueryResultsInput{QueryId: &queryID})
+			if err != nil {
+				if ctx.Err() != nil {
+					stop()
+				}
+				return
+			}
+			for _, r := range res.Results[sent:] {
+				row := ResultRow{}
+				for _, field := range r {
+					if field.Field != nil && field.Value != nil {
+						row[*field.Field] = *field.Value
+					}
+				}
+				select {
+				case rows <- row:
+				case <-ctx.Done():
+					stop()
+					return
+				}
+			}
+			sent = len(res.Results)
+
+			switch res.Status {
+			case types.QueryStatusComplete, types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout:
+				return
+			}
+		}
+	}
+}
+
+// resolveLogGroupNames combines LogGroupNames with whatever matches
+// LogGroupNamePrefixes via DescribeLogGroups.
+func resolveLogGroupNames(ctx context.Context, cwlV2 QueryAPI, input QueryInput) ([]string, error) {
+	names := append([]string{}, input.LogGroupNames...)
+	for _, prefix := range input.LogGroupNamePrefixes {
+		p := cloudwatchlogsV2.NewDescribeLogGroupsPaginator(cwlV2, &cloudwatchlogsV2.DescribeLogGroupsInput{
+			LogGroupNamePrefix: &prefix,
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range page.LogGroups {
+				if g.LogGroupName != nil {
+					names = append(names, *g.LogGroupName)
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+func awsInt64(v int64) *int64 {
+	return &v
+}
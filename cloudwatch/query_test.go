@@ -0,0 +1,142 @@
+package cloudwatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/Mimie1805/cw/cloudwatch/cwfake"
+)
+
+func resultRow(field, value string) types.ResultField {
+	return types.ResultField{Field: &field, Value: &value}
+}
+
+func TestQuery_DeliversRowsUntilComplete(t *testing.T) {
+	fake := &cwfake.Client{
+		QueryResultPages: []cwfake.QueryResultsPage{
+			{Output: &cloudwatchlogsV2.GetQueryResultsOutput{
+				Status:  types.QueryStatusRunning,
+				Results: [][]types.ResultField{{resultRow("@message", "first")}},
+			}},
+			{Output: &cloudwatchlogsV2.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+				Results: [][]types.ResultField{
+					{resultRow("@message", "first")},
+					{resultRow("@message", "second")},
+				},
+			}},
+		},
+	}
+
+	savedInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = savedInterval }()
+
+	rows, err := Query(context.Background(), fake, QueryInput{
+		Query:         "fields @message",
+		StartTime:     time.Unix(0, 0),
+		EndTime:       time.Unix(1, 0),
+		LogGroupNames: []string{"my-group"},
+	})
+	if err != nil {
+		t.Fatalf("Query returned error: %s", err)
+	}
+
+	var got []string
+	for row := range rows {
+		got = append(got, row["@message"])
+	}
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("got rows %v, want [first second]", got)
+	}
+}
+
+func TestQuery_StopsOnFailedStatus(t *testing.T) {
+	fake := &cwfake.Client{
+		QueryResultPages: []cwfake.QueryResultsPage{
+			{Output: &cloudwatchlogsV2.GetQueryResultsOutput{Status: types.QueryStatusFailed}},
+		},
+	}
+
+	savedInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = savedInterval }()
+
+	rows, err := Query(context.Background(), fake, QueryInput{
+		Query:         "fields @message",
+		LogGroupNames: []string{"my-group"},
+	})
+	if err != nil {
+		t.Fatalf("Query returned error: %s", err)
+	}
+
+	for range rows {
+		t.Fatalf("expected no rows for a failed query")
+	}
+}
+
+func TestQuery_CancelStopsQueryServerSide(t *testing.T) {
+	fake := &cwfake.Client{
+		QueryResultPages: []cwfake.QueryResultsPage{
+			{Output: &cloudwatchlogsV2.GetQueryResultsOutput{Status: types.QueryStatusRunning}},
+		},
+	}
+
+	savedInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = savedInterval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := Query(ctx, fake, QueryInput{
+		Query:         "fields @message",
+		LogGroupNames: []string{"my-group"},
+	})
+	if err != nil {
+		t.Fatalf("Query returned error: %s", err)
+	}
+
+	cancel()
+	for range rows {
+	}
+
+	if fake.StopQueryCalls != 1 {
+		t.Fatalf("got %d StopQuery calls, want 1", fake.StopQueryCalls)
+	}
+}
+
+func TestQuery_ResolvesLogGroupNamePrefixes(t *testing.T) {
+	groupName := "prefixed-group"
+	fake := &cwfake.Client{
+		LogGroups: []types.LogGroup{{LogGroupName: &groupName}},
+		QueryResultPages: []cwfake.QueryResultsPage{
+			{Output: &cloudwatchlogsV2.GetQueryResultsOutput{Status: types.QueryStatusComplete}},
+		},
+	}
+
+	savedInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = savedInterval }()
+
+	rows, err := Query(context.Background(), fake, QueryInput{
+		Query:                "fields @message",
+		LogGroupNamePrefixes: []string{"prefixed-"},
+	})
+	if err != nil {
+		t.Fatalf("Query returned error: %s", err)
+	}
+	for range rows {
+	}
+}
+
+func TestQuery_NoMatchingLogGroupsErrors(t *testing.T) {
+	fake := &cwfake.Client{}
+
+	_, err := Query(context.Background(), fake, QueryInput{Query: "fields @message"})
+	if err == nil {
+		t.Fatalf("expected an error when no log groups match")
+	}
+}
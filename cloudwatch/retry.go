@@ -0,0 +1,99 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// filterLogEventsPager is the subset of *cloudwatchlogsV2.FilterLogEventsPaginator
+// that nextPageWithRetry needs, kept as an interface so tests can fake a
+// paginator that returns synthetic throttling errors without hitting AWS.
+type filterLogEventsPager interface {
+	NextPage(ctx context.Context, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.FilterLogEventsOutput, error)
+}
+
+// nextPageWithRetry fetches one page, retrying transient errors according
+// to policy with decorrelated-jitter backoff. It returns the first
+// non-retryable error, or the last retryable one once MaxAttempts is
+// exhausted. metrics.observeFilterRequest is called once per attempt (not
+// just once for the whole call) so a request throttled twice before
+// succeeding still shows up as two "throttled" samples plus an "ok" one,
+// rather than a single sample that hides the retries. metrics may be nil.
+func nextPageWithRetry(ctx context.Context, p filterLogEventsPager, policy RetryPolicy, log *log.Logger, logGroupName string, metrics *tailMetrics) (*cloudwatchlogsV2.FilterLogEventsOutput, error) {
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		res, err := p.NextPage(ctx)
+		metrics.observeFilterRequest(time.Since(start), err)
+		if err == nil || !isRetryable(err) || attempt >= policy.MaxAttempts {
+			return res, err
+		}
+		delay = policy.Next(delay)
+		log.Printf("%s for %s, attempt %d/%d. Retrying in %s.\n", err.Error(), logGroupName, attempt, policy.MaxAttempts, delay)
+		time.Sleep(delay)
+	}
+}
+
+// RetryPolicy controls how Tail retries transient FilterLogEvents failures
+// (throttling, rate limiting, transient network errors) instead of giving
+// up on the first one. Delays follow the "decorrelated jitter" formula
+// from https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// DefaultRetryPolicy makes up to 5 attempts (so at most 4 retries) with
+// delays ranging from 250ms up to 30s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: 250 * time.Millisecond,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   3,
+	}
+}
+
+// Next returns the delay to wait before the given attempt, picking a
+// random value between InitialDelay and prev*Multiplier, capped at
+// MaxDelay. Pass the previous delay in (zero on the first attempt).
+func (p RetryPolicy) Next(prev time.Duration) time.Duration {
+	if p.InitialDelay <= 0 {
+		return 0
+	}
+	if prev < p.InitialDelay {
+		prev = p.InitialDelay
+	}
+	upper := time.Duration(float64(prev) * p.Multiplier)
+	if p.MaxDelay > 0 && upper > p.MaxDelay {
+		upper = p.MaxDelay
+	}
+	if upper <= p.InitialDelay {
+		return p.InitialDelay
+	}
+	return p.InitialDelay + time.Duration(rand.Int63n(int64(upper-p.InitialDelay)))
+}
+
+// isRetryable reports whether err is a transient CloudWatch Logs error
+// worth retrying under a RetryPolicy: throttling, rate limiting, or a
+// plain network error.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "ThrottlingException") || strings.Contains(msg, "LimitExceededException") {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,110 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakePager scripts a sequence of NextPage results, standing in for
+// *cloudwatchlogsV2.FilterLogEventsPaginator so the retry path can be
+// tested without a real FilterLogEvents API client.
+type fakePager struct {
+	results []pageResult
+	calls   int
+}
+
+type pageResult struct {
+	out *cloudwatchlogsV2.FilterLogEventsOutput
+	err error
+}
+
+func (f *fakePager) NextPage(ctx context.Context, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.FilterLogEventsOutput, error) {
+	r := f.results[f.calls]
+	f.calls++
+	return r.out, r.err
+}
+
+var errThrottling = errors.New("ThrottlingException: Rate exceeded")
+
+func TestNextPageWithRetry_RecoversFromThrottling(t *testing.T) {
+	want := &cloudwatchlogsV2.FilterLogEventsOutput{Events: []types.FilteredLogEvent{{}}}
+	p := &fakePager{results: []pageResult{
+		{err: errThrottling},
+		{err: errThrottling},
+		{out: want},
+	}}
+	policy := RetryPolicy{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Multiplier: 2}
+
+	got, err := nextPageWithRetry(context.Background(), p, policy, log.Default(), "my-group", nil)
+	if err != nil {
+		t.Fatalf("expected eventual success, got error: %s", err)
+	}
+	if got != want || p.calls != 3 {
+		t.Fatalf("expected 3 attempts ending in success, got %d calls", p.calls)
+	}
+}
+
+func TestNextPageWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	p := &fakePager{results: []pageResult{
+		{err: errThrottling},
+		{err: errThrottling},
+		{err: errThrottling},
+	}}
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Multiplier: 2}
+
+	_, err := nextPageWithRetry(context.Background(), p, policy, log.Default(), "my-group", nil)
+	if err == nil {
+		t.Fatal("expected the last throttling error to surface once attempts are exhausted")
+	}
+	if p.calls != 3 {
+		t.Fatalf("expected exactly MaxAttempts calls, got %d", p.calls)
+	}
+}
+
+func TestNextPageWithRetry_NonRetryableFailsImmediately(t *testing.T) {
+	boom := errors.New("AccessDeniedException: nope")
+	p := &fakePager{results: []pageResult{{err: boom}}}
+	policy := DefaultRetryPolicy()
+
+	_, err := nextPageWithRetry(context.Background(), p, policy, log.Default(), "my-group", nil)
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected immediate non-retryable error, got %v", err)
+	}
+	if p.calls != 1 {
+		t.Fatalf("expected no retries for a non-retryable error, got %d calls", p.calls)
+	}
+}
+
+func TestNextPageWithRetry_RecordsMetricsPerAttempt(t *testing.T) {
+	p := &fakePager{results: []pageResult{
+		{err: errThrottling},
+		{err: errThrottling},
+		{out: &cloudwatchlogsV2.FilterLogEventsOutput{}},
+	}}
+	policy := RetryPolicy{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Multiplier: 2}
+
+	reg := prometheus.NewRegistry()
+	metrics, err := newTailMetrics(reg, "my-group")
+	if err != nil {
+		t.Fatalf("newTailMetrics: %s", err)
+	}
+
+	if _, err := nextPageWithRetry(context.Background(), p, policy, log.Default(), "my-group", metrics); err != nil {
+		t.Fatalf("expected eventual success, got error: %s", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.filterRequests.WithLabelValues("my-group", "throttled")); got != 2 {
+		t.Fatalf("got %v throttled samples, want 2 (one per failed attempt)", got)
+	}
+	if got := testutil.ToFloat64(metrics.filterRequests.WithLabelValues("my-group", "ok")); got != 1 {
+		t.Fatalf("got %v ok samples, want 1", got)
+	}
+}
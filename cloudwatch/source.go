@@ -0,0 +1,316 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"gopkg.in/yaml.v3"
+)
+
+// GroupConfig describes a single log group to acquire from as part of a
+// Source. StreamName, when set, is a regex matched against discovered
+// stream names; StreamNamePrefix is passed straight through to
+// DescribeLogStreams as a server-side filter.
+type GroupConfig struct {
+	LogGroupName          string        `yaml:"log_group_name" json:"log_group_name"`
+	StreamNamePrefix      string        `yaml:"stream_name_prefix,omitempty" json:"stream_name_prefix,omitempty"`
+	StreamName            string        `yaml:"stream_name,omitempty" json:"stream_name,omitempty"`
+	MaxStreamAge          time.Duration `yaml:"max_stream_age,omitempty" json:"max_stream_age,omitempty"`
+	PollNewStreamInterval time.Duration `yaml:"poll_new_stream_interval,omitempty" json:"poll_new_stream_interval,omitempty"`
+	PollStreamInterval    time.Duration `yaml:"poll_stream_interval,omitempty" json:"poll_stream_interval,omitempty"`
+	StartTime             time.Time     `yaml:"start_time,omitempty" json:"start_time,omitempty"`
+	EndTime               time.Time     `yaml:"end_time,omitempty" json:"end_time,omitempty"`
+	AWSRegion             string        `yaml:"aws_region,omitempty" json:"aws_region,omitempty"`
+	AWSProfile            string        `yaml:"aws_profile,omitempty" json:"aws_profile,omitempty"`
+	Grep                  string        `yaml:"grep,omitempty" json:"grep,omitempty"`
+	GrepV                 string        `yaml:"grepv,omitempty" json:"grepv,omitempty"`
+
+	streamNameRe *regexp.Regexp
+}
+
+func (g *GroupConfig) matches(streamName string) bool {
+	if g.streamNameRe == nil {
+		return true
+	}
+	return g.streamNameRe.MatchString(streamName)
+}
+
+// SourceConfig is the top-level document consumed by LoadSourceConfig. It is
+// valid as either YAML or JSON (JSON is a subset of YAML).
+type SourceConfig struct {
+	Groups []GroupConfig `yaml:"groups" json:"groups"`
+}
+
+// LoadSourceConfig reads and parses a multi-group source config from path.
+func LoadSourceConfig(path string) (*SourceConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading source config %s: %w", path, err)
+	}
+
+	var cfg SourceConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing source config %s: %w", path, err)
+	}
+
+	for i := range cfg.Groups {
+		g := &cfg.Groups[i]
+		if g.LogGroupName == "" {
+			return nil, fmt.Errorf("group %d: log_group_name is required", i)
+		}
+		if g.StreamName != "" {
+			re, err := regexp.Compile(g.StreamName)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: invalid stream_name regex: %w", g.LogGroupName, err)
+			}
+			g.streamNameRe = re
+		}
+		if g.PollNewStreamInterval == 0 {
+			g.PollNewStreamInterval = 30 * time.Second
+		}
+		if g.PollStreamInterval == 0 {
+			g.PollStreamInterval = 5 * time.Second
+		}
+		if g.MaxStreamAge == 0 {
+			g.MaxStreamAge = 15 * time.Minute
+		}
+		if g.StartTime.IsZero() {
+			g.StartTime = time.Now()
+		}
+	}
+	return &cfg, nil
+}
+
+// ClientFactory returns the CloudWatch Logs client to use for a given
+// group, letting callers resolve aws_region/aws_profile however they see
+// fit (shared config, per-group sessions, etc).
+type ClientFactory func(group GroupConfig) (FilterLogEventsAPI, error)
+
+// Source is a multi-group acquisition subsystem: it discovers matching
+// streams in each configured log group on a poll interval, tails each one
+// in its own goroutine, and multiplexes every event onto a single output
+// channel. It is the fleet-wide analogue of Tail, which only handles one
+// log group at a time.
+//
+// Known gap: tailStream polls FilterLogEvents with its own loop instead of
+// going through Tail's, so it doesn't get Tail's RetryPolicy backoff on
+// throttling, its TailOptions/Prometheus metrics, or Checkpointer-backed
+// restart state - a ThrottlingException here just waits out
+// PollStreamInterval, and lastSeenTimestamp/dedup state is lost if the
+// process restarts. Tracked as follow-up work to fold tailStream's loop
+// into Tail's so Source gets those for free instead of reimplementing
+// them.
+type Source struct {
+	cfg     SourceConfig
+	clients ClientFactory
+	log     *log.Logger
+
+	out chan types.FilteredLogEvent
+	wg  sync.WaitGroup
+}
+
+// NewSource builds a Source from a config and a way to obtain a client per
+// group. logger may be nil, in which case a default logger is used.
+func NewSource(cfg *SourceConfig, clients ClientFactory, logger *log.Logger) *Source {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Source{
+		cfg:     *cfg,
+		clients: clients,
+		log:     logger,
+		out:     make(chan types.FilteredLogEvent, 1000),
+	}
+}
+
+// Run starts acquisition for every configured group and returns the
+// multiplexed event channel. The channel is closed once ctx is cancelled
+// and every per-stream goroutine has exited.
+func (s *Source) Run(ctx context.Context) (<-chan types.FilteredLogEvent, error) {
+	for _, g := range s.cfg.Groups {
+		cwlV2, err := s.clients(g)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", g.LogGroupName, err)
+		}
+		s.wg.Add(1)
+		go s.runGroup(ctx, g, cwlV2)
+	}
+
+	go func() {
+		s.wg.Wait()
+		close(s.out)
+	}()
+
+	return s.out, nil
+}
+
+// runGroup discovers streams for a single group on PollNewStreamInterval
+// and keeps one tailing goroutine per live stream, reaping those that have
+// gone quiet for longer than MaxStreamAge.
+func (s *Source) runGroup(ctx context.Context, g GroupConfig, cwlV2 FilterLogEventsAPI) {
+	defer s.wg.Done()
+
+	active := map[string]context.CancelFunc{}
+	var mu sync.Mutex
+
+	discover := func() {
+		streams, err := s.describeStreams(ctx, cwlV2, g)
+		if err != nil {
+			s.log.Printf("%s: describe streams failed: %s\n", g.LogGroupName, err)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		seen := map[string]bool{}
+		for _, name := range streams {
+			seen[name] = true
+			if _, ok := active[name]; ok {
+				continue
+			}
+			streamCtx, cancel := context.WithCancel(ctx)
+			active[name] = cancel
+			s.wg.Add(1)
+			go s.tailStream(streamCtx, g, cwlV2, name)
+		}
+		for name, cancel := range active {
+			if !seen[name] {
+				cancel()
+				delete(active, name)
+			}
+		}
+	}
+
+	discover()
+	ticker := time.NewTicker(g.PollNewStreamInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			for _, cancel := range active {
+				cancel()
+			}
+			mu.Unlock()
+			return
+		case <-ticker.C:
+			discover()
+		}
+	}
+}
+
+func (s *Source) describeStreams(ctx context.Context, cwlV2 FilterLogEventsAPI, g GroupConfig) ([]string, error) {
+	var names []string
+	p := cloudwatchlogsV2.NewDescribeLogStreamsPaginator(cwlV2, &cloudwatchlogsV2.DescribeLogStreamsInput{
+		LogGroupName:        &g.LogGroupName,
+		LogStreamNamePrefix: nonEmpty(g.StreamNamePrefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range page.LogStreams {
+			if st.LogStreamName == nil {
+				continue
+			}
+			if g.MaxStreamAge > 0 && st.LastEventTimestamp != nil {
+				age := time.Since(time.UnixMilli(*st.LastEventTimestamp))
+				if age > g.MaxStreamAge {
+					continue
+				}
+			}
+			if g.matches(*st.LogStreamName) {
+				names = append(names, *st.LogStreamName)
+			}
+		}
+	}
+	return names, nil
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// tailStream tails a single stream within a group, feeding matching events
+// onto the Source's shared output channel until streamCtx is cancelled.
+// Each poll re-queries from lastSeenTimestamp inclusive, so a dedup cache
+// (the same TTL cache Tail uses) is required to avoid re-delivering events
+// that land on that exact millisecond. See the Source doc comment's "known
+// gap" note: this poll loop is a separate implementation from Tail's and
+// doesn't share its retry/metrics/checkpoint handling.
+func (s *Source) tailStream(streamCtx context.Context, g GroupConfig, cwlV2 FilterLogEventsAPI, streamName string) {
+	defer s.wg.Done()
+
+	startTime := g.StartTime
+	re := compileGrepV(g.GrepV)
+	cache := createCache(60*time.Second, defaultPurgeFreq, s.log)
+	defer cache.Stop()
+
+	lastSeenTimestamp := startTime.Unix() * 1000
+	ticker := time.NewTicker(g.PollStreamInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-streamCtx.Done():
+			return
+		case <-ticker.C:
+			input := &cloudwatchlogsV2.FilterLogEventsInput{
+				LogGroupName:   &g.LogGroupName,
+				LogStreamNames: []string{streamName},
+				StartTime:      &lastSeenTimestamp,
+			}
+			if g.Grep != "" {
+				input.FilterPattern = &g.Grep
+			}
+			if !g.EndTime.IsZero() {
+				end := g.EndTime.Unix() * 1000
+				input.EndTime = &end
+			}
+
+			p := cloudwatchlogsV2.NewFilterLogEventsPaginator(cwlV2, input)
+			for p.HasMorePages() {
+				page, err := p.NextPage(streamCtx)
+				if err != nil {
+					s.log.Printf("%s/%s: %s\n", g.LogGroupName, streamName, err)
+					break
+				}
+				for _, event := range page.Events {
+					if re != nil && re.MatchString(*event.Message) {
+						continue
+					}
+					if cache.Has(*event.EventId) {
+						continue
+					}
+					cache.Add(*event.EventId, *event.Timestamp)
+					if *event.Timestamp > lastSeenTimestamp {
+						lastSeenTimestamp = *event.Timestamp
+					}
+					select {
+					case s.out <- event:
+					case <-streamCtx.Done():
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func compileGrepV(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile(pattern)
+}
@@ -0,0 +1,208 @@
+package cloudwatch
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	cloudwatchlogsV2 "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/Mimie1805/cw/cloudwatch/cwfake"
+)
+
+// sourceFakeClient is a scriptable FilterLogEventsAPI for exercising
+// Source's discover/reap/multiplex logic, which needs dynamic
+// DescribeLogStreams responses and per-stream call counts that cwfake.Client
+// (built for Tail's single-stream case) doesn't track.
+type sourceFakeClient struct {
+	mu sync.Mutex
+
+	streamsSeq    [][]types.LogStream
+	describeCalls int
+	discovered    chan struct{}
+
+	filterCallsByStream map[string]int
+}
+
+func (c *sourceFakeClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogsV2.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogStreamsOutput, error) {
+	c.mu.Lock()
+	i := c.describeCalls
+	if i >= len(c.streamsSeq) {
+		i = len(c.streamsSeq) - 1
+	}
+	streams := c.streamsSeq[i]
+	c.describeCalls++
+	c.mu.Unlock()
+
+	if c.discovered != nil {
+		c.discovered <- struct{}{}
+	}
+	return &cloudwatchlogsV2.DescribeLogStreamsOutput{LogStreams: streams}, nil
+}
+
+func (c *sourceFakeClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogsV2.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.DescribeLogGroupsOutput, error) {
+	return &cloudwatchlogsV2.DescribeLogGroupsOutput{}, nil
+}
+
+func (c *sourceFakeClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogsV2.FilterLogEventsInput, optFns ...func(*cloudwatchlogsV2.Options)) (*cloudwatchlogsV2.FilterLogEventsOutput, error) {
+	stream := params.LogStreamNames[0]
+	c.mu.Lock()
+	c.filterCallsByStream[stream]++
+	c.mu.Unlock()
+	return &cloudwatchlogsV2.FilterLogEventsOutput{}, nil
+}
+
+func logStream(name string) types.LogStream {
+	return types.LogStream{LogStreamName: &name}
+}
+
+// TestSource_RunGroup_ReapsStreamThatDisappears verifies a stream that drops
+// out of DescribeLogStreams between polls has its tailStream goroutine
+// cancelled: once reaped, it should stop generating FilterLogEvents calls.
+func TestSource_RunGroup_ReapsStreamThatDisappears(t *testing.T) {
+	fake := &sourceFakeClient{
+		streamsSeq: [][]types.LogStream{
+			{logStream("s1")},
+			{},
+		},
+		discovered:          make(chan struct{}, 10),
+		filterCallsByStream: map[string]int{},
+	}
+
+	g := GroupConfig{
+		LogGroupName:          "my-group",
+		PollNewStreamInterval: 5 * time.Millisecond,
+		PollStreamInterval:    time.Millisecond,
+		StartTime:             time.Now(),
+	}
+
+	s := NewSource(&SourceConfig{Groups: []GroupConfig{g}}, nil, discardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.wg.Add(1)
+	go s.runGroup(ctx, g, fake)
+
+	<-fake.discovered // initial discover, s1 active
+	<-fake.discovered // second discover under way; s1 is reaped as soon as it returns
+
+	fake.mu.Lock()
+	reapedAt := fake.filterCallsByStream["s1"]
+	fake.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	fake.mu.Lock()
+	afterReap := fake.filterCallsByStream["s1"]
+	fake.mu.Unlock()
+
+	if afterReap > reapedAt+1 {
+		t.Fatalf("expected s1's tailStream to stop polling once reaped, calls went from %d to %d", reapedAt, afterReap)
+	}
+}
+
+// TestSource_TailStream_StopsItsCacheWhenStreamCtxIsCancelled guards
+// against tailStream's per-stream ttlCache purge goroutine outliving a
+// reaped stream: in a Source with high stream turnover (the case this
+// feature targets), every reap must release its cache, not just its
+// FilterLogEvents polling.
+func TestSource_TailStream_StopsItsCacheWhenStreamCtxIsCancelled(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	fake := &sourceFakeClient{filterCallsByStream: map[string]int{}}
+	g := GroupConfig{
+		LogGroupName:       "my-group",
+		PollStreamInterval: time.Millisecond,
+		StartTime:          time.Now(),
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	s := NewSource(&SourceConfig{Groups: []GroupConfig{g}}, nil, discardLogger())
+	s.wg.Add(1)
+	go s.tailStream(streamCtx, g, fake, "s1")
+
+	time.Sleep(10 * time.Millisecond)
+	if got := runtime.NumGoroutine(); got <= before {
+		t.Fatalf("expected tailStream to have started its cache's purge goroutine, NumGoroutine went from %d to %d", before, got)
+	}
+
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected tailStream's cache purge goroutine to exit once streamCtx is cancelled, NumGoroutine stayed at %d (baseline %d)", runtime.NumGoroutine(), before)
+}
+
+// TestSource_Run_MultiplexesEventsFromMultipleGroups verifies events from
+// distinct groups and streams all land on the single channel Run returns.
+func TestSource_Run_MultiplexesEventsFromMultipleGroups(t *testing.T) {
+	g1 := GroupConfig{
+		LogGroupName:          "group-a",
+		PollNewStreamInterval: time.Hour,
+		PollStreamInterval:    time.Millisecond,
+		StartTime:             time.Now(),
+	}
+	g2 := GroupConfig{
+		LogGroupName:          "group-b",
+		PollNewStreamInterval: time.Hour,
+		PollStreamInterval:    time.Millisecond,
+		StartTime:             time.Now(),
+	}
+
+	clientA := &cwfake.Client{
+		LogStreams:  []types.LogStream{logStream("stream-a")},
+		FilterPages: []cwfake.FilterLogEventsPage{cwfake.Page(cwfake.Event("a-1", "stream-a", "hello from a", 1000))},
+	}
+	clientB := &cwfake.Client{
+		LogStreams:  []types.LogStream{logStream("stream-b")},
+		FilterPages: []cwfake.FilterLogEventsPage{cwfake.Page(cwfake.Event("b-1", "stream-b", "hello from b", 2000))},
+	}
+
+	clients := ClientFactory(func(g GroupConfig) (FilterLogEventsAPI, error) {
+		if g.LogGroupName == "group-a" {
+			return clientA, nil
+		}
+		return clientB, nil
+	})
+
+	s := NewSource(&SourceConfig{Groups: []GroupConfig{g1, g2}}, clients, discardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, err := s.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+
+	got := map[string]bool{}
+	timeout := time.After(2 * time.Second)
+	for len(got) < 2 {
+		select {
+		case event := <-out:
+			got[*event.EventId] = true
+		case <-timeout:
+			t.Fatalf("timed out waiting for events from both groups, got %v", got)
+		}
+	}
+	if !got["a-1"] || !got["b-1"] {
+		t.Fatalf("expected events from both groups, got %v", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("expected out channel to close once ctx is cancelled")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for out channel to close")
+	}
+}
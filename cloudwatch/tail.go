@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"sync"
 	"time"
@@ -13,21 +12,38 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 )
 
-type logStreamsType struct {
-	groupStreams []string
-	sync.RWMutex
+// streamRegistry is a race-safe set of stream names, keyed by name so
+// reset() can replace the whole set in one shot without handing out a
+// reference a concurrent caller could mutate. Snapshot() always returns a
+// defensive copy.
+type streamRegistry struct {
+	mu      sync.RWMutex
+	streams map[string]struct{}
 }
 
-func (s *logStreamsType) reset(groupStreams []string) {
-	s.Lock()
-	defer s.Unlock()
-	s.groupStreams = groupStreams
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{streams: make(map[string]struct{})}
 }
 
-func (s *logStreamsType) get() []string {
-	s.Lock()
-	defer s.Unlock()
-	return s.groupStreams
+func (r *streamRegistry) reset(streamNames []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.streams = make(map[string]struct{}, len(streamNames))
+	for _, name := range streamNames {
+		r.streams[name] = struct{}{}
+	}
+}
+
+// Snapshot returns a defensive copy of the current stream names so callers
+// can iterate it freely while a concurrent reset is in flight.
+func (r *streamRegistry) Snapshot() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.streams))
+	for name := range r.streams {
+		names = append(names, name)
+	}
+	return names
 }
 
 func params(logGroupName string, streamNames []string,
@@ -54,7 +70,20 @@ func params(logGroupName string, streamNames []string,
 
 type gs func() ([]string, error)
 
-func initialiseStreams(getStreams gs, retry *bool, idle chan<- bool, logStreams *logStreamsType) error {
+func streamName(event types.FilteredLogEvent) string {
+	if event.LogStreamName == nil {
+		return ""
+	}
+	return *event.LogStreamName
+}
+
+// initialiseStreams seeds logStreams with the initial set of matching
+// streams, retrying while the log group doesn't exist yet, then keeps
+// refreshing it on a 5s ticker until ctx is cancelled. The ticker goroutine
+// is tied to ctx so callers that don't want a background refresh (follow
+// false, or Tail exiting) can stop it rather than leaking it for the life
+// of the process.
+func initialiseStreams(ctx context.Context, getStreams gs, retry *bool, idle chan<- bool, logStreams *streamRegistry) error {
 	input := make(chan time.Time, 1)
 	input <- time.Now()
 
@@ -78,26 +107,44 @@ func initialiseStreams(getStreams gs, retry *bool, idle chan<- bool, logStreams
 	}
 	t := time.NewTicker(time.Second * 5)
 	go func() {
-		for range t.C {
-			s, _ := getStreams()
-			// s, _ := getStreams(logGroupName, logStreamName)
-			if s != nil {
-				logStreams.reset(s)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s, _ := getStreams()
+				if s != nil {
+					logStreams.reset(s)
+				}
 			}
 		}
 	}()
 	return nil
 }
 
-//Tail tails the given stream names in the specified log group name
-//To tail all the available streams logStreamName has to be '*'
-//It returns a channel where logs line are published
-//Unless the follow flag is true the channel is closed once there are no more events available
-func Tail(cwlV2 *cloudwatchlogsV2.Client,
+// Tail tails the given stream names in the specified log group name
+// To tail all the available streams logStreamName has to be '*'
+// It returns a channel where log lines are published, and a second channel
+// that receives a single error before both channels are closed. Transient
+// errors (throttling, rate limiting, network blips) are retried internally
+// according to opts.RetryPolicy; one reaches the error channel only once
+// the policy's MaxAttempts is exhausted. Any other error is sent at once.
+// opts may be nil to take every default.
+// Unless the follow flag is true the log channel is closed once there are no more events available
+// The log channel is buffered but delivery is not guaranteed: if the
+// caller falls behind and the channel is full, an event is dropped rather
+// than blocking the poll loop. Drops are logged and, when
+// opts.MetricsRegisterer is set, counted in cw_events_dropped_total.
+func Tail(cwlV2 FilterLogEventsAPI,
 	logGroupName *string, logStreamName *string, follow *bool, retry *bool,
 	startTime *time.Time, endTime *time.Time,
 	grep *string, grepv *string,
-	limiter <-chan time.Time, log *log.Logger) (<-chan types.FilteredLogEvent, error) {
+	limiter <-chan time.Time, log *log.Logger, opts *TailOptions) (<-chan types.FilteredLogEvent, <-chan error, error) {
+
+	if opts == nil {
+		opts = &TailOptions{}
+	}
 
 	lastSeenTimestamp := startTime.Unix() * 1000
 	var endTimeInMillis int64
@@ -106,12 +153,48 @@ func Tail(cwlV2 *cloudwatchlogsV2.Client,
 	}
 
 	ch := make(chan types.FilteredLogEvent, 1000)
+	errCh := make(chan error, 1)
 	idle := make(chan bool, 1)
 
+	policy := DefaultRetryPolicy()
+	if opts.RetryPolicy != nil {
+		policy = *opts.RetryPolicy
+	}
+	metrics, err := newTailMetrics(opts.MetricsRegisterer, *logGroupName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("registering metrics for %s: %w", *logGroupName, err)
+	}
+
 	ttl := 60 * time.Second
 	cache := createCache(ttl, defaultPurgeFreq, log)
 
-	logStreams := &logStreamsType{}
+	// checkpointState accumulates the full State that is handed to Save on
+	// each flush; it starts from whatever Checkpoint.Load returns, seeding lastSeenTimestamp and the dedup cache so a
+	// restarted Tail neither replays nor drops events.
+	var checkpointState State
+	var lastFlush time.Time
+	if opts.Checkpoint != nil {
+		loaded, err := opts.Checkpoint.Load(*logGroupName, nil)
+		if err != nil {
+			cache.Stop()
+			return nil, nil, fmt.Errorf("loading checkpoint for %s: %w", *logGroupName, err)
+		}
+		checkpointState = loaded
+		if loaded.LastSeenTimestamp > lastSeenTimestamp {
+			lastSeenTimestamp = loaded.LastSeenTimestamp
+		}
+		for _, id := range loaded.EventIDs {
+			cache.Add(id, loaded.LastSeenTimestamp)
+		}
+	}
+
+	// refreshCtx bounds the background stream-list refresh ticker started
+	// by initialiseStreams: it's cancelled as soon as we know we won't need
+	// it again (non-follow runs) or once the tail goroutine below returns,
+	// so the ticker never outlives its caller.
+	refreshCtx, cancelRefresh := context.WithCancel(context.Background())
+
+	logStreams := newStreamRegistry()
 
 	if logStreamName != nil && *logStreamName != "" || *retry {
 		getStreams := func(logGroupName *string, logStreamName *string) ([]string, error) {
@@ -140,69 +223,35 @@ func Tail(cwlV2 *cloudwatchlogsV2.Client,
 			return streams, nil
 		}
 
-		e := initialiseStreams(func() ([]string, error) {
+		e := initialiseStreams(refreshCtx, func() ([]string, error) {
 			return getStreams(logGroupName, logStreamName)
 		}, retry, idle, logStreams)
 		if e != nil {
-			return nil, e
+			cancelRefresh()
+			cache.Stop()
+			return nil, nil, e
 		}
-
-		// input := make(chan time.Time, 1)
-		// input <- time.Now()
-
-		// for range input {
-		// 	s, e := getStreams(logGroupName, logStreamName)
-		// 	if e != nil {
-		// 		if e.Error() == "ResourceNotFoundException" && *retry {
-		// 			log.Println("log group not available. retry in 150 milliseconds.")
-		// 			timer := time.After(time.Millisecond * 150)
-		// 			input <- <-timer
-		// 		} else {
-		// 			return nil, e
-		// 		}
-		// 	} else {
-		// 		//found streams, seed them and exit the check loop
-		// 		logStreams.reset(s)
-		// 		idle <- true
-		// 		close(input)
-		// 	}
-		// }
-		// t := time.NewTicker(time.Second * 5)
-		// go func() {
-		// 	for range t.C {
-		// 		s, _ := getStreams(logGroupName, logStreamName)
-		// 		if s != nil {
-		// 			logStreams.reset(s)
-		// 		}
-		// 	}
-		// }()
 	} else {
 		idle <- true
 	}
 	re := regexp.MustCompile(*grepv)
 	go func() {
+		defer cancelRefresh()
+		defer cache.Stop()
 		for range limiter {
 			select {
 			case <-idle:
-				logParam := params(*logGroupName, logStreams.get(), lastSeenTimestamp, endTimeInMillis, grep, follow)
+				streamNames := logStreams.Snapshot()
+				metrics.setOpenStreams(len(streamNames))
+				logParam := params(*logGroupName, streamNames, lastSeenTimestamp, endTimeInMillis, grep, follow)
 				paginator := cloudwatchlogsV2.NewFilterLogEventsPaginator(cwlV2, logParam)
+				var aborted bool
 				for paginator.HasMorePages() {
-					res, err := paginator.NextPage(context.TODO())
+					res, err := nextPageWithRetry(context.TODO(), paginator, policy, log, *logGroupName, metrics)
 					if err != nil {
-						if err.Error() == "ThrottlingException" {
-							log.Printf("Rate exceeded for %s. Wait for 250ms then retry.\n", *logGroupName)
-
-							//Wait and fire request again. 1 Retry allowed.
-							time.Sleep(250 * time.Millisecond)
-							res, err = paginator.NextPage(context.TODO())
-							if err != nil {
-								fmt.Fprintln(os.Stderr, err.Error())
-								os.Exit(1)
-							}
-						} else {
-							fmt.Fprintln(os.Stderr, err.Error())
-							os.Exit(1)
-						}
+						errCh <- err
+						aborted = true
+						break
 					}
 					for _, event := range res.Events {
 						if *grepv == "" || !re.MatchString(*event.Message) {
@@ -216,16 +265,43 @@ func Tail(cwlV2 *cloudwatchlogsV2.Client,
 									lastSeenTimestamp = eventTimestamp
 								}
 								cache.Add(*event.EventId, *event.Timestamp)
-								ch <- event
+								metrics.incStreamHit(streamName(event))
+								metrics.setLastEventTimestamp(eventTimestamp)
+								checkpointState.pushEventID(*event.EventId)
+								select {
+								case ch <- event:
+								default:
+									metrics.incEventsDropped()
+									log.Printf("%s: event channel full, dropped event %s\n", *logGroupName, *event.EventId)
+								}
 							} else {
 								log.Printf("%s already seen\n", *event.EventId)
 							}
 						}
 					}
 
+					if opts.Checkpoint != nil && time.Since(lastFlush) >= opts.CheckpointFlushInterval {
+						checkpointState.LogGroupName = *logGroupName
+						checkpointState.LastSeenTimestamp = lastSeenTimestamp
+						checkpointState.Streams = streamNames
+						if err := opts.Checkpoint.Save(*logGroupName, checkpointState); err != nil {
+							log.Printf("checkpoint save for %s failed: %s\n", *logGroupName, err)
+						} else {
+							lastFlush = time.Now()
+						}
+					}
+				}
+				if aborted {
+					cancelRefresh()
+					close(ch)
+					close(errCh)
+					return
 				}
 				if !*follow {
+					cancelRefresh()
 					close(ch)
+					close(errCh)
+					return
 				} else {
 					log.Println("last page")
 					idle <- true
@@ -235,5 +311,5 @@ func Tail(cwlV2 *cloudwatchlogsV2.Client,
 			}
 		}
 	}()
-	return ch, nil
+	return ch, errCh, nil
 }
@@ -0,0 +1,224 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/Mimie1805/cw/cloudwatch/cwfake"
+)
+
+func discardLogger() *log.Logger {
+	return log.New(ioutil.Discard, "", 0)
+}
+
+func TestTail_NonFollow(t *testing.T) {
+	cases := []struct {
+		name       string
+		pages      []cwfake.FilterLogEventsPage
+		grep       string
+		grepv      string
+		wantEvents []string
+	}{
+		{
+			name:       "delivers every event and closes",
+			pages:      []cwfake.FilterLogEventsPage{cwfake.Page(cwfake.Event("1", "s", "hello", 1000))},
+			wantEvents: []string{"1"},
+		},
+		{
+			name: "grepv drops matching messages",
+			pages: []cwfake.FilterLogEventsPage{cwfake.Page(
+				cwfake.Event("1", "s", "keep me", 1000),
+				cwfake.Event("2", "s", "drop noisy", 2000),
+			)},
+			grepv:      "noisy",
+			wantEvents: []string{"1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &cwfake.Client{FilterPages: tc.pages}
+			limiter := make(chan time.Time, 1)
+			limiter <- time.Now()
+
+			logGroup, logStream, grep, grepv := "my-group", "", tc.grep, tc.grepv
+			follow, retry := false, false
+			start, end := time.Unix(0, 0), time.Time{}
+
+			ch, errCh, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, discardLogger(), nil)
+			if err != nil {
+				t.Fatalf("Tail returned error: %s", err)
+			}
+
+			var gotIDs []string
+			for event := range ch {
+				gotIDs = append(gotIDs, *event.EventId)
+			}
+			if len(gotIDs) != len(tc.wantEvents) {
+				t.Fatalf("got %d events %v, want %v", len(gotIDs), gotIDs, tc.wantEvents)
+			}
+			for i, id := range tc.wantEvents {
+				if gotIDs[i] != id {
+					t.Fatalf("event %d: got %s, want %s", i, gotIDs[i], id)
+				}
+			}
+
+			select {
+			case e, ok := <-errCh:
+				if ok {
+					t.Fatalf("unexpected error on error channel: %s", e)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("errCh was not closed after the event channel drained; callers ranging both channels would hang")
+			}
+		})
+	}
+}
+
+func TestTail_DedupAcrossPolls(t *testing.T) {
+	fake := &cwfake.Client{FilterPages: []cwfake.FilterLogEventsPage{
+		cwfake.Page(cwfake.Event("dup-1", "s", "first", 1000)),
+		cwfake.Page(cwfake.Event("dup-1", "s", "first-again", 1000), cwfake.Event("2", "s", "second", 2000)),
+	}}
+	limiter := make(chan time.Time, 2)
+	limiter <- time.Now()
+	limiter <- time.Now()
+
+	logGroup, logStream, grep, grepv := "my-group", "", "", ""
+	follow, retry := true, false
+	start, end := time.Unix(0, 0), time.Time{}
+
+	ch, _, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, discardLogger(), nil)
+	if err != nil {
+		t.Fatalf("Tail returned error: %s", err)
+	}
+
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < 2 {
+		select {
+		case event := <-ch:
+			got = append(got, *event.EventId)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %v so far", got)
+		}
+	}
+	if got[0] != "dup-1" || got[1] != "2" {
+		t.Fatalf("expected the repeated dup-1 to be filtered out, got %v", got)
+	}
+}
+
+func TestTail_DropsAndLogsWhenChannelFull(t *testing.T) {
+	events := make([]types.FilteredLogEvent, 0, 1001)
+	for i := 0; i < 1001; i++ {
+		events = append(events, cwfake.Event(fmt.Sprintf("%d", i), "s", "flood", int64(i+1)))
+	}
+	fake := &cwfake.Client{FilterPages: []cwfake.FilterLogEventsPage{cwfake.Page(events...)}}
+	limiter := make(chan time.Time, 1)
+	limiter <- time.Now()
+
+	var logBuf bytes.Buffer
+	logger := log.New(&logBuf, "", 0)
+
+	logGroup, logStream, grep, grepv := "my-group", "", "", ""
+	follow, retry := false, false
+	start, end := time.Unix(0, 0), time.Time{}
+
+	ch, _, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, logger, nil)
+	if err != nil {
+		t.Fatalf("Tail returned error: %s", err)
+	}
+
+	// Give the producer goroutine time to enqueue (and overflow) before we
+	// start draining, so the drop isn't masked by a concurrent reader
+	// keeping pace with the flood.
+	time.Sleep(50 * time.Millisecond)
+
+	var got int
+	for range ch {
+		got++
+	}
+	if got != 1000 {
+		t.Fatalf("expected exactly the 1000-capacity channel's worth of events, got %d", got)
+	}
+	if !strings.Contains(logBuf.String(), "dropped event") {
+		t.Fatalf("expected a log line reporting the dropped event, got log output: %q", logBuf.String())
+	}
+}
+
+func TestTail_RetriesThrottlingWithoutSurfacingError(t *testing.T) {
+	fake := &cwfake.Client{FilterPages: []cwfake.FilterLogEventsPage{
+		cwfake.ErrorPage(cwfake.ThrottlingError()),
+		cwfake.ErrorPage(cwfake.ThrottlingError()),
+		cwfake.Page(cwfake.Event("1", "s", "ok", 1000)),
+	}}
+	limiter := make(chan time.Time, 1)
+	limiter <- time.Now()
+
+	logGroup, logStream, grep, grepv := "my-group", "", "", ""
+	follow, retry := false, false
+	start, end := time.Unix(0, 0), time.Time{}
+	opts := &TailOptions{RetryPolicy: &RetryPolicy{
+		MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Multiplier: 2,
+	}}
+
+	ch, errCh, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, discardLogger(), opts)
+	if err != nil {
+		t.Fatalf("Tail returned error: %s", err)
+	}
+
+	var gotIDs []string
+	for event := range ch {
+		gotIDs = append(gotIDs, *event.EventId)
+	}
+	if len(gotIDs) != 1 || gotIDs[0] != "1" {
+		t.Fatalf("expected the event to arrive once throttling recovers, got %v", gotIDs)
+	}
+
+	select {
+	case e, ok := <-errCh:
+		if ok {
+			t.Fatalf("throttling is retried internally and must not reach the error channel, got %s", e)
+		}
+	default:
+	}
+}
+
+func TestTail_NonRetryableErrorSurfacesOnErrorChannel(t *testing.T) {
+	errAccessDenied := errors.New("AccessDeniedException: not authorized")
+	fake := &cwfake.Client{FilterPages: []cwfake.FilterLogEventsPage{
+		cwfake.ErrorPage(errAccessDenied),
+	}}
+	limiter := make(chan time.Time, 1)
+	limiter <- time.Now()
+
+	logGroup, logStream, grep, grepv := "my-group", "", "", ""
+	follow, retry := false, false
+	start, end := time.Unix(0, 0), time.Time{}
+
+	ch, errCh, err := Tail(fake, &logGroup, &logStream, &follow, &retry, &start, &end, &grep, &grepv, limiter, discardLogger(), nil)
+	if err != nil {
+		t.Fatalf("Tail returned error: %s", err)
+	}
+
+	select {
+	case gotErr := <-errCh:
+		if gotErr != errAccessDenied {
+			t.Fatalf("got error %v, want %v", gotErr, errAccessDenied)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the non-retryable error")
+	}
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected the event channel to be closed after a non-retryable error")
+	}
+}
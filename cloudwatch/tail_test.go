@@ -0,0 +1,43 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestStreamRegistryConcurrentAccess exercises reset() and Snapshot() from
+// many goroutines at once. Run with -race: the old logStreamsType handed
+// out its backing slice by reference from get(), so a concurrent reset
+// racing with an in-progress iteration could corrupt it.
+func TestStreamRegistryConcurrentAccess(t *testing.T) {
+	r := newStreamRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			r.reset([]string{fmt.Sprintf("stream-%d-a", i), fmt.Sprintf("stream-%d-b", i)})
+		}(i)
+		go func() {
+			defer wg.Done()
+			for _, name := range r.Snapshot() {
+				_ = len(name)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStreamRegistrySnapshotIsDefensiveCopy(t *testing.T) {
+	r := newStreamRegistry()
+	r.reset([]string{"a", "b"})
+
+	snap := r.Snapshot()
+	r.reset([]string{"c"})
+
+	if len(snap) != 2 {
+		t.Fatalf("expected snapshot taken before reset to keep its own 2 entries, got %d", len(snap))
+	}
+}